@@ -0,0 +1,134 @@
+package runcmd
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+)
+
+type identExtraEnv struct{}
+type identEnvMap struct{}
+type identUnsetEnv struct{}
+
+// WithExtraEnv appends environ (in "key=value" form) onto os.Environ(),
+// as opposed to WithEnv which replaces the environment outright. Later
+// WithExtraEnv/WithEnvMap/WithUnsetEnv calls compose: duplicate keys keep
+// the last occurrence, matching the semantics "os/exec" uses for Cmd.Env.
+func (ctx *rcCtx) WithExtraEnv(environ ...string) Ctx {
+	existing, _ := ctx.Context.Value(identExtraEnv{}).([]string)
+	ctx.Context = context.WithValue(ctx.Context, identExtraEnv{}, append(existing, environ...))
+	return ctx
+}
+
+// WithEnvMap is a convenience wrapper around WithExtraEnv that accepts a
+// map[string]string instead of "key=value" strings.
+func (ctx *rcCtx) WithEnvMap(environ map[string]string) Ctx {
+	list := make([]string, 0, len(environ))
+	for k, v := range environ {
+		list = append(list, k+"="+v)
+	}
+	return ctx.WithExtraEnv(list...)
+}
+
+// WithUnsetEnv removes the named variables from the environment that
+// WithExtraEnv/WithEnvMap would otherwise compose from os.Environ(),
+// regardless of the order in which WithUnsetEnv is called relative to
+// them.
+func (ctx *rcCtx) WithUnsetEnv(keys ...string) Ctx {
+	existing, _ := ctx.Context.Value(identUnsetEnv{}).([]string)
+	ctx.Context = context.WithValue(ctx.Context, identUnsetEnv{}, append(existing, keys...))
+	return ctx
+}
+
+// envKey returns the "key" portion of a "key=value" environment variable
+// entry. Windows drive-letter pseudo-variables (e.g. "=C:=C:\\foo") start
+// with "=" themselves, so for those the key runs up to the *second* "=",
+// not the first, mirroring "os/exec"'s dedupEnvCase.
+func envKey(kv string) string {
+	if strings.HasPrefix(kv, "=") {
+		if i := strings.IndexByte(kv[1:], '='); i >= 0 {
+			return kv[:i+1]
+		}
+		return kv
+	}
+	if i := strings.IndexByte(kv, '='); i >= 0 {
+		return kv[:i]
+	}
+	return kv
+}
+
+// envKeyEqual compares environment variable keys using the same
+// case-folding "os/exec" uses for Cmd.Env: case-insensitive on Windows,
+// case-sensitive everywhere else. It also special-cases Windows's
+// leading-"=" drive-letter variables (e.g. "=C:=C:\\foo"), whose key
+// includes that leading "=" and so is never considered to collide with a
+// normal variable.
+func envKeyEqual(a, b string) bool {
+	return envKeyEqualOS(a, b, runtime.GOOS)
+}
+
+// envKeyEqualOS is envKeyEqual parameterized by GOOS, split out so the
+// Windows comparison rules can be unit-tested from any host.
+func envKeyEqualOS(a, b, goos string) bool {
+	if goos == "windows" {
+		if (len(a) > 0 && a[0] == '=') != (len(b) > 0 && b[0] == '=') {
+			return false
+		}
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// ResolveEnv computes the environment that Create would assign to
+// cmd.Env for ctx: os.Environ() overlaid with WithExtraEnv/WithEnvMap
+// entries (minus any WithUnsetEnv keys), keeping the last occurrence of
+// each key, or the raw slice from WithEnv if that was used instead. It is
+// exported mainly so callers can assert on it in tests without spawning
+// a command.
+func ResolveEnv(ctx context.Context) []string {
+	var raw []string
+	if err := getStringSlice(ctx, &raw, identEnv{}, "Env"); err == nil && raw != nil {
+		return raw
+	}
+
+	extra, _ := ctx.Value(identExtraEnv{}).([]string)
+	unset, _ := ctx.Value(identUnsetEnv{}).([]string)
+	if extra == nil && unset == nil {
+		return nil
+	}
+
+	base := append([]string{}, os.Environ()...)
+	merged := append(base, extra...)
+
+	unwanted := func(key string) bool {
+		for _, u := range unset {
+			if envKeyEqual(key, u) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []string
+	for _, kv := range merged {
+		key := envKey(kv)
+		if unwanted(key) {
+			continue
+		}
+
+		replaced := false
+		for i, existing := range out {
+			if envKeyEqual(envKey(existing), key) {
+				out[i] = kv
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, kv)
+		}
+	}
+
+	return out
+}
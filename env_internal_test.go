@@ -0,0 +1,37 @@
+package runcmd
+
+import "testing"
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		kv   string
+		want string
+	}{
+		{"FOO=bar", "FOO"},
+		{"FOO=", "FOO"},
+		{"FOO", "FOO"},
+		{"=C:=C:\\foo", "=C:"},
+		{"=D:=D:\\bar", "=D:"},
+	}
+
+	for _, tt := range tests {
+		if got := envKey(tt.kv); got != tt.want {
+			t.Errorf(`envKey(%q) = %q, want %q`, tt.kv, got, tt.want)
+		}
+	}
+}
+
+func TestEnvKeyEqualOS(t *testing.T) {
+	if !envKeyEqualOS("FOO", "foo", "windows") {
+		t.Error(`envKeyEqualOS should be case-insensitive on windows`)
+	}
+	if envKeyEqualOS("FOO", "foo", "linux") {
+		t.Error(`envKeyEqualOS should be case-sensitive outside windows`)
+	}
+	if envKeyEqualOS("=C:", "=D:", "windows") {
+		t.Error(`envKeyEqualOS should not conflate distinct drive-letter keys`)
+	}
+	if envKeyEqualOS("=C:", "C", "windows") {
+		t.Error(`envKeyEqualOS should not equate a leading-"=" key with a normal one`)
+	}
+}
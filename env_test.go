@@ -0,0 +1,52 @@
+package runcmd_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEnvExtraEnvDedup(t *testing.T) {
+	os.Setenv("RUNCMD_TEST_VAR", "original")
+	defer os.Unsetenv("RUNCMD_TEST_VAR")
+
+	ctx := runcmd.Context(context.Background()).WithExtraEnv("RUNCMD_TEST_VAR=overridden")
+	environ := runcmd.ResolveEnv(ctx)
+
+	var found string
+	for _, kv := range environ {
+		if kv == "RUNCMD_TEST_VAR=overridden" {
+			found = kv
+		}
+		assert.NotEqual(t, "RUNCMD_TEST_VAR=original", kv, `WithExtraEnv should keep the last occurrence`)
+	}
+	assert.Equal(t, "RUNCMD_TEST_VAR=overridden", found)
+}
+
+func TestResolveEnvMap(t *testing.T) {
+	ctx := runcmd.Context(context.Background()).WithEnvMap(map[string]string{"RUNCMD_TEST_MAP": "v"})
+	environ := runcmd.ResolveEnv(ctx)
+	assert.Contains(t, environ, "RUNCMD_TEST_MAP=v")
+}
+
+func TestResolveEnvUnset(t *testing.T) {
+	os.Setenv("RUNCMD_TEST_UNSET", "v")
+	defer os.Unsetenv("RUNCMD_TEST_UNSET")
+
+	ctx := runcmd.Context(context.Background()).WithUnsetEnv("RUNCMD_TEST_UNSET")
+	environ := runcmd.ResolveEnv(ctx)
+	for _, kv := range environ {
+		assert.NotContains(t, kv, "RUNCMD_TEST_UNSET=", `WithUnsetEnv should remove the variable`)
+	}
+}
+
+func TestResolveEnvRawEnvTakesPrecedence(t *testing.T) {
+	ctx := runcmd.Context(context.Background()).
+		WithEnv("FOO=bar").
+		WithExtraEnv("BAZ=quux")
+
+	assert.Equal(t, []string{"FOO=bar"}, runcmd.ResolveEnv(ctx), `WithEnv should still fully replace the environment`)
+}
@@ -0,0 +1,103 @@
+package runcmd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+type identObserver struct{}
+
+// Observer receives lifecycle callbacks around a command's execution.
+// BeforeStart is called just before the command is started, and AfterExit
+// once it has exited (or failed to start).
+type Observer interface {
+	BeforeStart(*exec.Cmd)
+	AfterExit(*exec.Cmd, error, time.Duration)
+}
+
+// LineObserver is an optional capability of an Observer: if an Observer
+// passed to WithObserver also implements LineObserver, Create wraps
+// cmd.Stdout/cmd.Stderr in a line-splitting io.Writer so OnStdoutLine/
+// OnStderrLine are invoked once per line of output (with the trailing
+// newline stripped), in addition to the output still reaching its
+// original destination.
+type LineObserver interface {
+	OnStdoutLine(string)
+	OnStderrLine(string)
+}
+
+// WithObserver installs an Observer that Run notifies around the
+// command's execution, and that Create uses to wrap cmd.Stdout/cmd.Stderr
+// with per-line callbacks when obs also implements LineObserver.
+func (ctx *rcCtx) WithObserver(obs Observer) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identObserver{}, obs)
+	return ctx
+}
+
+func getObserver(ctx context.Context) Observer {
+	v, _ := ctx.Value(identObserver{}).(Observer)
+	return v
+}
+
+// lineWriter splits whatever is written to it on newlines and invokes fn
+// with each complete line, in addition to passing everything through to
+// dst unmodified.
+type lineWriter struct {
+	dst    io.Writer
+	fn     func(string)
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func newLineWriter(dst io.Writer, fn func(string)) *lineWriter {
+	r, w := io.Pipe()
+	lw := &lineWriter{dst: dst, fn: fn, reader: r, writer: w}
+	go lw.scan()
+	return lw
+}
+
+func (lw *lineWriter) scan() {
+	scanner := bufio.NewScanner(lw.reader)
+	for scanner.Scan() {
+		lw.fn(scanner.Text())
+	}
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	if n, err := lw.dst.Write(p); err != nil {
+		return n, err
+	}
+	return lw.writer.Write(p)
+}
+
+func (lw *lineWriter) Close() error {
+	return lw.writer.Close()
+}
+
+func wrapObserverWriters(cmd *exec.Cmd, obs Observer) {
+	lo, ok := obs.(LineObserver)
+	if !ok {
+		return
+	}
+	cmd.Stdout = newLineWriter(cmd.Stdout, lo.OnStdoutLine)
+	cmd.Stderr = newLineWriter(cmd.Stderr, lo.OnStderrLine)
+}
+
+// closeObserverWriters closes any lineWriters wrapObserverWriters
+// installed on cmd, so their scanning goroutines observe EOF and exit. It
+// must only close *lineWriter specifically, not any io.Closer found in
+// cmd.Stdout/cmd.Stderr: by default those fields are os.Stdout/os.Stderr
+// themselves (or a caller-supplied *os.File via WithStderr), and closing
+// those out from under the calling process would be wrong regardless of
+// whether an Observer was ever configured.
+func closeObserverWriters(cmd *exec.Cmd) {
+	if lw, ok := cmd.Stdout.(*lineWriter); ok {
+		_ = lw.Close()
+	}
+	if lw, ok := cmd.Stderr.(*lineWriter); ok {
+		_ = lw.Close()
+	}
+}
@@ -0,0 +1,61 @@
+package observer
+
+import (
+	"context"
+	"os/exec"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTelObserver starts a span around a command's execution, recording its
+// argv, working directory, duration, and exit code, and marking the span
+// as an error when the command fails.
+type OTelObserver struct {
+	Tracer trace.Tracer
+
+	ctx  context.Context
+	span trace.Span
+}
+
+// NewOTelObserver returns an OTelObserver that starts spans against ctx
+// using tracer. If tracer is nil, otel.Tracer("github.com/lestrrat-go/runcmd") is used.
+func NewOTelObserver(ctx context.Context, tracer trace.Tracer) *OTelObserver {
+	if tracer == nil {
+		tracer = otel.Tracer("github.com/lestrrat-go/runcmd")
+	}
+	return &OTelObserver{Tracer: tracer, ctx: ctx}
+}
+
+func (o *OTelObserver) BeforeStart(cmd *exec.Cmd) {
+	_, span := o.Tracer.Start(o.ctx, "runcmd.Run",
+		trace.WithAttributes(
+			attribute.StringSlice("runcmd.args", cmd.Args),
+			attribute.String("runcmd.dir", cmd.Dir),
+		),
+	)
+	o.span = span
+}
+
+func (o *OTelObserver) AfterExit(cmd *exec.Cmd, err error, duration time.Duration) {
+	if o.span == nil {
+		return
+	}
+	defer o.span.End()
+
+	o.span.SetAttributes(attribute.Int64("runcmd.duration_ms", duration.Milliseconds()))
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		o.span.SetAttributes(attribute.Int("runcmd.exit_code", exitErr.ExitCode()))
+	} else if cmd.ProcessState != nil {
+		o.span.SetAttributes(attribute.Int("runcmd.exit_code", cmd.ProcessState.ExitCode()))
+	}
+
+	if err != nil {
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+}
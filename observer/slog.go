@@ -0,0 +1,49 @@
+// Package observer provides ready-made runcmd.Observer implementations so
+// callers get structured command auditing without hand-rolling wrappers
+// around every call to runcmd.Run.
+package observer
+
+import (
+	"log/slog"
+	"os/exec"
+	"time"
+)
+
+// SlogObserver logs a command's lifecycle through a *slog.Logger: one
+// debug-level message before the command starts, carrying the command
+// line and working directory, and one info (or error, on failure) message
+// after it exits, which adds the duration and, when the process ran to
+// completion or failed with an *exec.ExitError, the exit code.
+type SlogObserver struct {
+	Logger *slog.Logger
+}
+
+// NewSlogObserver returns a SlogObserver that logs through logger. If
+// logger is nil, slog.Default() is used.
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogObserver{Logger: logger}
+}
+
+func (o *SlogObserver) BeforeStart(cmd *exec.Cmd) {
+	o.Logger.Debug("starting command", "args", cmd.Args, "dir", cmd.Dir)
+}
+
+func (o *SlogObserver) AfterExit(cmd *exec.Cmd, err error, duration time.Duration) {
+	attrs := []any{"args", cmd.Args, "dir", cmd.Dir, "duration", duration}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		attrs = append(attrs, "exit_code", exitErr.ExitCode())
+	}
+
+	if err != nil {
+		o.Logger.Error("command failed", append(attrs, "error", err)...)
+		return
+	}
+
+	if cmd.ProcessState != nil {
+		attrs = append(attrs, "exit_code", cmd.ProcessState.ExitCode())
+	}
+	o.Logger.Info("command finished", attrs...)
+}
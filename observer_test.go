@@ -0,0 +1,81 @@
+package runcmd_test
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeObserver struct {
+	mu          sync.Mutex
+	beforeCount int
+	afterCount  int
+	afterErr    error
+	stdoutLines []string
+	stderrLines []string
+}
+
+func (o *fakeObserver) BeforeStart(*exec.Cmd) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.beforeCount++
+}
+
+func (o *fakeObserver) AfterExit(_ *exec.Cmd, err error, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.afterCount++
+	o.afterErr = err
+}
+
+func (o *fakeObserver) OnStdoutLine(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stdoutLines = append(o.stdoutLines, line)
+}
+
+func (o *fakeObserver) OnStderrLine(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stderrLines = append(o.stderrLines, line)
+}
+
+func TestObserverLifecycle(t *testing.T) {
+	obs := &fakeObserver{}
+	ctx := runcmd.Context(context.Background()).WithObserver(obs)
+
+	err := runcmd.Run(ctx, "echo", "hi")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	assert.Equal(t, 1, obs.beforeCount, `BeforeStart should be called exactly once`)
+	assert.Equal(t, 1, obs.afterCount, `AfterExit should be called exactly once`)
+	assert.NoError(t, obs.afterErr)
+}
+
+func TestObserverLineCallbacks(t *testing.T) {
+	obs := &fakeObserver{}
+	ctx := runcmd.Context(context.Background()).WithObserver(obs)
+
+	err := runcmd.Run(ctx, "sh", "-c", "echo one; echo two; echo err 1>&2")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The scanning goroutines race the process exit; give them a moment
+	// to drain before asserting.
+	time.Sleep(100 * time.Millisecond)
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	assert.Equal(t, []string{"one", "two"}, obs.stdoutLines)
+	assert.Equal(t, []string{"err"}, obs.stderrLines)
+}
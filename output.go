@@ -0,0 +1,130 @@
+package runcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+type identCaptureStderr struct{}
+
+// Error wraps a failed command with the context needed to produce a
+// useful error message: the captured stderr, exit code, argv, working
+// directory, and how long the command ran before failing. Unwrap returns
+// the underlying *exec.ExitError, so callers can still errors.As through
+// to it.
+type Error struct {
+	Path     string
+	Args     []string
+	Dir      string
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+	err      *exec.ExitError
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf(`command %q failed with exit code %d`, append([]string{e.Path}, e.Args...), e.ExitCode)
+	if len(e.Stderr) > 0 {
+		msg += fmt.Sprintf(`: %s`, bytes.TrimSpace(e.Stderr))
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// WithCaptureStderr arranges for the command's stderr to be captured into
+// buf in addition to whatever WithStderr already sends it to. Output and
+// CombinedOutput use this internally to populate Error.Stderr; callers of
+// Run/Create may also set it directly to inspect stderr after the fact.
+func (ctx *rcCtx) WithCaptureStderr(buf *bytes.Buffer) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identCaptureStderr{}, buf)
+	return ctx
+}
+
+func getCaptureStderr(ctx context.Context) *bytes.Buffer {
+	v, _ := ctx.Value(identCaptureStderr{}).(*bytes.Buffer)
+	return v
+}
+
+// Output runs the command and returns its standard output, mirroring
+// (*exec.Cmd).Output. If the command exits non-zero, the returned error
+// is a *runcmd.Error wrapping the *exec.ExitError, with Stderr populated
+// from the command's stderr. A *RetryError or *TimeoutError (from
+// WithRetry or WithTimeout) is returned as-is instead.
+func Output(ctx context.Context, path string, args ...string) ([]byte, error) {
+	return output(ctx, path, args, false)
+}
+
+// CombinedOutput runs the command and returns its combined standard
+// output and standard error, mirroring (*exec.Cmd).CombinedOutput. If the
+// command exits non-zero, the returned error is a *runcmd.Error wrapping
+// the *exec.ExitError. A *RetryError or *TimeoutError (from WithRetry or
+// WithTimeout) is returned as-is instead.
+func CombinedOutput(ctx context.Context, path string, args ...string) ([]byte, error) {
+	return output(ctx, path, args, true)
+}
+
+func output(ctx context.Context, path string, args []string, combined bool) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	stderr := getCaptureStderr(ctx)
+	if stderr == nil {
+		stderr = new(bytes.Buffer)
+	}
+
+	rc := Context(ctx).WithStdout(&stdout).WithCaptureStderr(stderr)
+	if combined {
+		rc = rc.WithStderr(&stdout)
+	} else {
+		rc = rc.WithStderr(stderr)
+	}
+
+	var dir string
+	_ = getString(ctx, &dir, identDir{}, "Dir")
+
+	reset := func() {
+		stdout.Reset()
+		stderr.Reset()
+	}
+
+	start := time.Now()
+	err := dispatch(rc, path, args, reset)
+	duration := time.Since(start)
+	if err != nil {
+		// RetryError/TimeoutError carry their own (richer) diagnostics;
+		// pass them through as-is instead of flattening them into Error.
+		// RetryError is checked first since a retried, timed-out command
+		// returns a *RetryError whose last attempt unwraps to a
+		// *TimeoutError, and the aggregate is the more useful of the two.
+		var retryErr *RetryError
+		if errors.As(err, &retryErr) {
+			return stdout.Bytes(), retryErr
+		}
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
+			return stdout.Bytes(), timeoutErr
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.Bytes(), &Error{
+				Path:     path,
+				Args:     args,
+				Dir:      dir,
+				Stderr:   stderr.Bytes(),
+				ExitCode: exitErr.ExitCode(),
+				Duration: duration,
+				err:      exitErr,
+			}
+		}
+		return stdout.Bytes(), fmt.Errorf(`failed to run command: %w`, err)
+	}
+
+	return stdout.Bytes(), nil
+}
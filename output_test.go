@@ -0,0 +1,45 @@
+package runcmd_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	out, err := runcmd.Output(context.Background(), "echo", "hello")
+	if !assert.NoError(t, err, `runcmd.Output should succeed`) {
+		return
+	}
+	assert.Equal(t, "hello\n", string(out))
+}
+
+func TestOutputError(t *testing.T) {
+	_, err := runcmd.Output(context.Background(), "sh", "-c", "echo oops 1>&2; exit 1")
+	if !assert.Error(t, err, `runcmd.Output should fail for a non-zero exit`) {
+		return
+	}
+
+	var rcErr *runcmd.Error
+	if !assert.True(t, errors.As(err, &rcErr), `error should be a *runcmd.Error`) {
+		return
+	}
+	assert.Equal(t, 1, rcErr.ExitCode)
+	assert.Contains(t, string(rcErr.Stderr), "oops")
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr), `errors.As should still reach the underlying *exec.ExitError`)
+}
+
+func TestCombinedOutput(t *testing.T) {
+	out, err := runcmd.CombinedOutput(context.Background(), "sh", "-c", "echo out; echo err 1>&2")
+	if !assert.NoError(t, err, `runcmd.CombinedOutput should succeed`) {
+		return
+	}
+	assert.Contains(t, string(out), "out")
+	assert.Contains(t, string(out), "err")
+}
@@ -0,0 +1,146 @@
+package runcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+type identPipeline struct{}
+
+// PipeStage describes a single command within a Pipeline. Ctx configures
+// that stage (env, dir, stderr, ...) independently of the other stages;
+// Path and Args identify the command to run.
+type PipeStage struct {
+	Ctx  context.Context
+	Path string
+	Args []string
+}
+
+// WithPipe appends one or more stages to ctx, turning the command that
+// ctx is eventually used with (via Run) into the first stage of a
+// shell-style pipeline such as `cat foo | grep bar | wc -l`. Each stage's
+// Stdout is wired to the next stage's Stdin; the final stage's Stdout is
+// ctx's own WithStdout when set, and otherwise the final stage's own
+// (os.Stdout by default).
+//
+// Use WithPipe to build a pipeline incrementally off a single Ctx; use
+// Pipeline directly when every stage's Ctx, Path, and Args are already in
+// hand.
+func (ctx *rcCtx) WithPipe(next ...PipeStage) Ctx {
+	existing := getPipeline(ctx.Context)
+	ctx.Context = context.WithValue(ctx.Context, identPipeline{}, append(existing, next...))
+	return ctx
+}
+
+func getPipeline(ctx context.Context) []PipeStage {
+	v, _ := ctx.Value(identPipeline{}).([]PipeStage)
+	return v
+}
+
+// stripPipeline returns ctx with any WithPipe stages cleared. Pipeline
+// uses it before handing an individual stage's Ctx to Create, so Create's
+// "use Run or Pipeline instead" guard doesn't misfire on the very stage
+// Pipeline is in the middle of expanding.
+func stripPipeline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identPipeline{}, []PipeStage(nil))
+}
+
+// Pipeline runs stages as a shell-style pipeline: each stage's Stdout is
+// connected to the next stage's Stdin via an io.Pipe, every stage is
+// started in order, and Pipeline waits for all of them to finish.
+//
+// The first stage's Stdin and the last stage's Stdout are taken from
+// their own Ctx (WithStdin/WithStdout), defaulting to os.Stdin/os.Stdout
+// as usual; WithStderr/WithEnv/WithDir apply per-stage, and any Observer
+// installed via WithObserver still receives its line callbacks. Each
+// stage's Ctx should normally be derived from a common parent
+// context.Context, so that cancelling the parent tears down every stage.
+//
+// The returned error, if any, joins every stage's error, so callers can
+// still errors.As through to individual per-stage *exec.ExitError values.
+func Pipeline(stages ...PipeStage) error {
+	if len(stages) == 0 {
+		return nil
+	}
+
+	readers := make([]*io.PipeReader, len(stages)-1)
+	writers := make([]*io.PipeWriter, len(stages)-1)
+	for i := range writers {
+		readers[i], writers[i] = io.Pipe()
+	}
+
+	cmds := make([]*exec.Cmd, len(stages))
+	for i, stage := range stages {
+		ctx := stripPipeline(stage.Ctx)
+		if i < len(writers) {
+			// Route this stage's Stdout into the next stage's Stdin
+			// instead of whatever WithStdout/default would otherwise
+			// apply; Create still wraps it for any LineObserver.
+			ctx = Context(ctx).WithStdout(writers[i])
+		}
+
+		cmd, err := Create(ctx, stage.Path, stage.Args...)
+		if err != nil {
+			closePipes(readers, writers)
+			return fmt.Errorf(`failed to create stage %d (%q): %w`, i, stage.Path, err)
+		}
+		if i > 0 {
+			cmd.Stdin = readers[i-1]
+		}
+		cmds[i] = cmd
+	}
+
+	var started []int
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			killStarted(cmds, started)
+			closePipes(readers, writers)
+			return fmt.Errorf(`failed to start stage %d (%q): %w`, i, stages[i].Path, err)
+		}
+		started = append(started, i)
+	}
+
+	var errs []error
+	for i, cmd := range cmds {
+		err := cmd.Wait()
+		closeObserverWriters(cmd)
+		if i < len(writers) {
+			writers[i].Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf(`stage %d (%q): %w`, i, stages[i].Path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// killStarted kills and reaps every already-started stage in started, so
+// that a later stage failing to start doesn't leave earlier stages
+// running as orphans.
+func killStarted(cmds []*exec.Cmd, started []int) {
+	for _, i := range started {
+		if cmds[i].Process != nil {
+			_ = cmds[i].Process.Kill()
+		}
+	}
+	for _, i := range started {
+		_ = cmds[i].Wait()
+	}
+}
+
+func closePipes(readers []*io.PipeReader, writers []*io.PipeWriter) {
+	for _, r := range readers {
+		if r != nil {
+			_ = r.Close()
+		}
+	}
+	for _, w := range writers {
+		if w != nil {
+			_ = w.Close()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package runcmd_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	var out bytes.Buffer
+
+	stages := []runcmd.PipeStage{
+		{
+			Ctx:  runcmd.Context(context.Background()).WithStdin(bytes.NewBufferString("foo\nbar\nfoo\n")),
+			Path: "grep",
+			Args: []string{"foo"},
+		},
+		{
+			Ctx:  runcmd.Context(context.Background()).WithStdout(&out),
+			Path: "wc",
+			Args: []string{"-l"},
+		},
+	}
+
+	err := runcmd.Pipeline(stages...)
+	if !assert.NoError(t, err, `runcmd.Pipeline should succeed`) {
+		return
+	}
+	assert.Contains(t, out.String(), "2", `output should report 2 matching lines`)
+}
+
+func TestRunWithPipe(t *testing.T) {
+	var out bytes.Buffer
+
+	catStage := runcmd.PipeStage{
+		Ctx:  runcmd.Context(context.Background()).WithStdout(&out),
+		Path: "cat",
+	}
+
+	ctx := runcmd.Context(context.Background()).
+		WithStdin(bytes.NewBufferString("hello\n")).
+		WithPipe(catStage)
+
+	err := runcmd.Run(ctx, "cat")
+	if !assert.NoError(t, err, `runcmd.Run with WithPipe should succeed, not be rejected by Create's pipeline guard`) {
+		return
+	}
+	assert.Equal(t, "hello\n", out.String())
+}
+
+func TestPipelineStartFailureKillsStartedStages(t *testing.T) {
+	stages := []runcmd.PipeStage{
+		{
+			Ctx:  runcmd.Context(context.Background()),
+			Path: "sleep",
+			Args: []string{"5"},
+		},
+		{
+			Ctx:  runcmd.Context(context.Background()),
+			Path: "/no/such/binary",
+		},
+	}
+
+	err := runcmd.Pipeline(stages...)
+	assert.Error(t, err, `runcmd.Pipeline should fail when a stage fails to start`)
+}
@@ -0,0 +1,231 @@
+package runcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"time"
+)
+
+type identRetry struct{}
+
+// RetryPolicy configures WithRetry's retry/backoff behavior. Delay
+// between attempts starts at InitialDelay, is multiplied by Multiplier
+// after each failed attempt, capped at MaxDelay, and jittered by +/-
+// Jitter (a fraction between 0 and 1) to avoid thundering-herd retries.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	Multiplier   float64
+	MaxDelay     time.Duration
+	Jitter       float64
+
+	// ShouldRetry decides whether attempt (1-indexed) should be retried
+	// given the error it produced. It defaults to retrying on any
+	// non-nil error except context cancellation and exec.ErrNotFound.
+	ShouldRetry func(attempt int, err error) bool
+}
+
+func (p RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(attempt, err)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return false
+	}
+	return true
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.InitialDelay) * pow(p.Multiplier, attempt-1)
+	if max := float64(p.MaxDelay); p.MaxDelay > 0 && d > max {
+		d = max
+	}
+	if p.Jitter > 0 {
+		delta := d * p.Jitter
+		d += (rand.Float64()*2 - 1) * delta
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func pow(base float64, exp int) float64 {
+	result := 1.0
+	for i := 0; i < exp; i++ {
+		result *= base
+	}
+	return result
+}
+
+// WithRetry makes Run retry the command according to policy, re-creating
+// the *exec.Cmd for each attempt (exec.Cmd is single-use). If WithStdin
+// was set with a reader that does not implement io.Seeker, its content is
+// snapshotted up front and replayed for every attempt, since the original
+// reader is consumed after the first run; an io.Seeker is instead rewound
+// to its starting offset before each attempt.
+func (ctx *rcCtx) WithRetry(policy RetryPolicy) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identRetry{}, policy)
+	return ctx
+}
+
+func getRetry(ctx context.Context) (RetryPolicy, bool) {
+	v, ok := ctx.Value(identRetry{}).(RetryPolicy)
+	return v, ok
+}
+
+// RetryAttempt records the outcome of a single attempt made by a retried
+// Run.
+type RetryAttempt struct {
+	Err      error
+	Duration time.Duration
+}
+
+// RetryError is returned by Run when every attempt permitted by a
+// RetryPolicy failed. Unwrap returns the last attempt's error.
+type RetryError struct {
+	Attempts []RetryAttempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf(`command failed after %d attempt(s): %s`, len(e.Attempts), e.Attempts[len(e.Attempts)-1].Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// runWithRetry runs path/args according to policy, re-creating the
+// command for each attempt. If hasStdinSnapshot is set, stdinSnapshot is
+// replayed verbatim on every attempt in place of whatever WithStdin set on
+// ctx. beforeAttempt,
+// if non-nil, runs before every attempt including the first; callers like
+// Output use it to reset their capture buffers between attempts.
+func runWithRetry(ctx context.Context, path string, args []string, policy RetryPolicy, stdinSnapshot []byte, hasStdinSnapshot bool, beforeAttempt func()) error {
+	var attempts []RetryAttempt
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if beforeAttempt != nil {
+			beforeAttempt()
+		}
+
+		attemptCtx := ctx
+		if hasStdinSnapshot {
+			attemptCtx = Context(ctx).WithStdin(bytes.NewReader(stdinSnapshot))
+		}
+
+		start := time.Now()
+		err := runOnce(attemptCtx, path, args)
+		duration := time.Since(start)
+		attempts = append(attempts, RetryAttempt{Err: err, Duration: duration})
+
+		if err == nil {
+			return nil
+		}
+		if attempt == maxAttempts || !policy.shouldRetry(attempt, err) {
+			return &RetryError{Attempts: attempts}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &RetryError{Attempts: append(attempts, RetryAttempt{Err: ctx.Err()})}
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+
+	return &RetryError{Attempts: attempts}
+}
+
+// dispatch runs path/args against ctx, retrying per ctx's WithRetry policy
+// if one is set and otherwise running once. beforeAttempt is passed
+// through to runWithRetry; it's ignored when no retry policy applies.
+func dispatch(ctx context.Context, path string, args []string, beforeAttempt func()) error {
+	if policy, ok := getRetry(ctx); ok {
+		snapshot, hasSnapshot, err := snapshotStdin(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasSnapshot {
+			var stdin io.Reader
+			_ = getReader(ctx, &stdin, identStdin{}, "Stdin")
+			if s, ok := stdin.(io.Seeker); ok {
+				if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+					next := beforeAttempt
+					beforeAttempt = func() {
+						_, _ = s.Seek(pos, io.SeekStart)
+						if next != nil {
+							next()
+						}
+					}
+				}
+			}
+		}
+		return runWithRetry(ctx, path, args, policy, snapshot, hasSnapshot, beforeAttempt)
+	}
+	return runOnce(ctx, path, args)
+}
+
+// runOnce performs a single, non-retried attempt, going through the same
+// Create/timeout/observer machinery Run uses, but without re-entering the
+// retry check (the identRetry value, if any, is simply ignored here).
+func runOnce(ctx context.Context, path string, args []string) error {
+	cmd, err := Create(ctx, path, args...)
+	if err != nil {
+		return fmt.Errorf(`failed to create *exec.Cmd: %w`, err)
+	}
+
+	obs := getObserver(ctx)
+	if obs != nil {
+		obs.BeforeStart(cmd)
+	}
+
+	start := time.Now()
+	if d, ok := getTimeout(ctx); ok {
+		err = runWithTimeout(cmd, d, getKillGracePeriod(ctx), getProcessGroup(ctx, true))
+	} else {
+		err = cmd.Run()
+	}
+	closeObserverWriters(cmd)
+
+	if obs != nil {
+		obs.AfterExit(cmd, err, time.Since(start))
+	}
+
+	return err
+}
+
+// snapshotStdin reads ctx's configured stdin (if any) fully into memory
+// so it can be replayed across retry attempts. Readers that also support
+// io.Seeker are not snapshotted (dispatch rewinds them in place instead),
+// since they're already replayable and may be too large to buffer.
+func snapshotStdin(ctx context.Context) (snapshot []byte, has bool, err error) {
+	var stdin io.Reader
+	if err := getReader(ctx, &stdin, identStdin{}, "Stdin"); err != nil {
+		return nil, false, err
+	}
+	if stdin == nil {
+		return nil, false, nil
+	}
+	if _, ok := stdin.(io.Seeker); ok {
+		return nil, false, nil
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, false, fmt.Errorf(`failed to snapshot Stdin for retry: %w`, err)
+	}
+	return data, true, nil
+}
@@ -0,0 +1,66 @@
+package runcmd_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetryExhaustsAttempts(t *testing.T) {
+	var calls int32
+
+	policy := runcmd.RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		Multiplier:   1,
+		ShouldRetry: func(attempt int, err error) bool {
+			atomic.AddInt32(&calls, 1)
+			return true
+		},
+	}
+
+	ctx := runcmd.Context(context.Background()).WithRetry(policy)
+	err := runcmd.Run(ctx, "false")
+
+	var retryErr *runcmd.RetryError
+	if !assert.True(t, errors.As(err, &retryErr), `error should be a *runcmd.RetryError`) {
+		return
+	}
+	assert.Len(t, retryErr.Attempts, 3, `all 3 attempts should have been made`)
+	// ShouldRetry is only consulted when the attempt count hasn't already
+	// reached MaxAttempts (reaching it short-circuits the check), so the
+	// final, 3rd attempt's failure never calls back into ShouldRetry.
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestWithRetrySucceedsWithoutExhausting(t *testing.T) {
+	policy := runcmd.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}
+	ctx := runcmd.Context(context.Background()).WithRetry(policy)
+
+	err := runcmd.Run(ctx, "true")
+	assert.NoError(t, err, `a command that succeeds on the first attempt should not be retried`)
+}
+
+func TestWithRetryStopsWhenShouldRetryDeclines(t *testing.T) {
+	policy := runcmd.RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: time.Millisecond,
+		ShouldRetry: func(attempt int, err error) bool {
+			return attempt < 2
+		},
+	}
+
+	ctx := runcmd.Context(context.Background()).WithRetry(policy)
+	err := runcmd.Run(ctx, "false")
+
+	var retryErr *runcmd.RetryError
+	if !assert.True(t, errors.As(err, &retryErr)) {
+		return
+	}
+	assert.Len(t, retryErr.Attempts, 2, `retry should stop as soon as ShouldRetry declines`)
+}
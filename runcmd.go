@@ -5,11 +5,13 @@
 package runcmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"time"
 )
 
 type identEnv struct{}
@@ -39,6 +41,50 @@ type Ctx interface {
 	// WithEnv specifies the list of environment variables that should be
 	// enabled in the command
 	WithEnv(...string) Ctx
+
+	// WithExtraEnv appends the given "key=value" pairs onto os.Environ()
+	// instead of replacing the environment outright; see ResolveEnv for
+	// the exact merge semantics.
+	WithExtraEnv(...string) Ctx
+
+	// WithEnvMap is a map[string]string convenience wrapper around
+	// WithExtraEnv.
+	WithEnvMap(map[string]string) Ctx
+
+	// WithUnsetEnv removes the named variables from the environment that
+	// WithExtraEnv/WithEnvMap would otherwise compose.
+	WithUnsetEnv(...string) Ctx
+
+	// WithPipe appends one or more stages to this Ctx, turning the command
+	// it is eventually used with into the first stage of a shell-style
+	// pipeline (see Pipeline).
+	WithPipe(...PipeStage) Ctx
+
+	// WithCaptureStderr arranges for the command's stderr to also be
+	// captured into buf; see Output and CombinedOutput.
+	WithCaptureStderr(*bytes.Buffer) Ctx
+
+	// WithTimeout bounds how long the command may run before Run kills
+	// it and returns a *runcmd.TimeoutError.
+	WithTimeout(time.Duration) Ctx
+
+	// WithKillGracePeriod controls how long Run waits after SIGTERM
+	// before escalating to SIGKILL on timeout. Defaults to 5 seconds.
+	WithKillGracePeriod(time.Duration) Ctx
+
+	// WithProcessGroup controls whether the command is started in its
+	// own process group so that timeout teardown reaches every
+	// descendant process, not just the direct child. Defaults to true
+	// whenever WithTimeout is set.
+	WithProcessGroup(bool) Ctx
+
+	// WithObserver installs an Observer that Run/Create notify around the
+	// command's lifecycle and, optionally, its output.
+	WithObserver(Observer) Ctx
+
+	// WithRetry makes Run retry the command according to policy,
+	// returning a *runcmd.RetryError if every attempt fails.
+	WithRetry(RetryPolicy) Ctx
 }
 
 type rcCtx struct {
@@ -145,20 +191,32 @@ func getStringSlice(ctx context.Context, dst *[]string, key interface{}, name st
 // pass runcmd.Ctx object as the first argument. To do this, create a
 // runcmd.Ctx object and use the various `WithXXX()` methods with it.
 func Run(ctx context.Context, path string, args ...string) error {
-	cmd, err := Create(ctx, path, args...)
-	if err != nil {
-		return fmt.Errorf(`failed to create *exec.Cmd: %w`, err)
+	if stages := getPipeline(ctx); len(stages) > 0 {
+		head := PipeStage{Ctx: ctx, Path: path, Args: args}
+		all := append([]PipeStage{head}, stages...)
+		var stdout io.Writer
+		if err := getWriter(ctx, &stdout, identStdout{}, "Stdout"); err != nil {
+			return fmt.Errorf(`failed to assign Stdout: %w`, err)
+		}
+		if stdout != nil {
+			last := &all[len(all)-1]
+			last.Ctx = Context(last.Ctx).WithStdout(stdout)
+		}
+		return Pipeline(all...)
 	}
 
-	return cmd.Run()
+	return dispatch(ctx, path, args, nil)
 }
 
 func Create(ctx context.Context, path string, args ...string) (*exec.Cmd, error) {
+	if stages := getPipeline(ctx); len(stages) > 0 {
+		return nil, fmt.Errorf(`ctx has pipeline stages attached via WithPipe; use Run or Pipeline instead of Create`)
+	}
+
 	var stdin io.Reader = os.Stdin
 	var stdout io.Writer = os.Stdout
 	var stderr io.Writer = os.Stderr
 	var dir string
-	var environ []string
 	if err := getWriter(ctx, &stdout, identStdout{}, "Stdout"); err != nil {
 		return nil, fmt.Errorf(`failed to assign Stdout: %w`, err)
 	}
@@ -171,8 +229,9 @@ func Create(ctx context.Context, path string, args ...string) (*exec.Cmd, error)
 	if err := getString(ctx, &dir, identDir{}, "Dir"); err != nil {
 		return nil, fmt.Errorf(`failed to assign Dir: %w`, err)
 	}
-	if err := getStringSlice(ctx, &environ, identEnv{}, "Env"); err != nil {
-		return nil, fmt.Errorf(`failed to assign Env: %w`, err)
+	environ := ResolveEnv(ctx)
+	if buf := getCaptureStderr(ctx); buf != nil && stderr != io.Writer(buf) {
+		stderr = io.MultiWriter(stderr, buf)
 	}
 
 	cmd := exec.CommandContext(ctx, path, args...)
@@ -191,6 +250,9 @@ func Create(ctx context.Context, path string, args ...string) (*exec.Cmd, error)
 	if environ != nil {
 		cmd.Env = environ
 	}
+	if obs := getObserver(ctx); obs != nil {
+		wrapObserverWriters(cmd, obs)
+	}
 
 	return cmd, nil
 }
@@ -0,0 +1,124 @@
+package runcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+type identTimeout struct{}
+type identKillGracePeriod struct{}
+type identProcessGroup struct{}
+
+const defaultKillGracePeriod = 5 * time.Second
+
+// WithTimeout bounds how long the command is allowed to run. On timeout,
+// Run terminates the whole process group (see WithProcessGroup) rather
+// than just the direct child, waiting up to WithKillGracePeriod before
+// escalating from SIGTERM to SIGKILL, and returns a *runcmd.TimeoutError.
+func (ctx *rcCtx) WithTimeout(d time.Duration) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identTimeout{}, d)
+	return ctx
+}
+
+// WithKillGracePeriod controls how long Run waits after sending SIGTERM
+// (on timeout) before escalating to SIGKILL. It defaults to 5 seconds and
+// has no effect unless WithTimeout is also set.
+func (ctx *rcCtx) WithKillGracePeriod(d time.Duration) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identKillGracePeriod{}, d)
+	return ctx
+}
+
+// WithProcessGroup controls whether the command is started in its own
+// process group (Setpgid on Unix, a new process group on Windows), which
+// lets Run signal every descendant process on timeout instead of just the
+// direct child. It defaults to true whenever WithTimeout is set.
+func (ctx *rcCtx) WithProcessGroup(enabled bool) Ctx {
+	ctx.Context = context.WithValue(ctx.Context, identProcessGroup{}, enabled)
+	return ctx
+}
+
+// TimeoutError is returned by Run when the command is killed because it
+// exceeded its WithTimeout duration. Unwrap returns the underlying
+// *exec.ExitError describing how the process actually terminated.
+type TimeoutError struct {
+	Timeout time.Duration
+	err     *exec.ExitError
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf(`command timed out after %s`, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.err
+}
+
+func getTimeout(ctx context.Context) (time.Duration, bool) {
+	v, ok := ctx.Value(identTimeout{}).(time.Duration)
+	return v, ok
+}
+
+func getKillGracePeriod(ctx context.Context) time.Duration {
+	v, ok := ctx.Value(identKillGracePeriod{}).(time.Duration)
+	if !ok {
+		return defaultKillGracePeriod
+	}
+	return v
+}
+
+func getProcessGroup(ctx context.Context, timeoutSet bool) bool {
+	v, ok := ctx.Value(identProcessGroup{}).(bool)
+	if !ok {
+		return timeoutSet
+	}
+	return v
+}
+
+// runWithTimeout runs cmd to completion, enforcing d by terminating cmd's
+// process group (SIGTERM, then SIGKILL after grace) if it's still running
+// once d elapses. It does not rely on exec.CommandContext's cancellation,
+// which only ever signals the direct child.
+func runWithTimeout(cmd *exec.Cmd, d, grace time.Duration, processGroup bool) error {
+	if processGroup {
+		setProcessGroup(cmd)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	terminate(cmd, processGroup, syscall.SIGTERM)
+
+	graceTimer := time.NewTimer(grace)
+	defer graceTimer.Stop()
+
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		errors.As(err, &exitErr)
+		return &TimeoutError{Timeout: d, err: exitErr}
+	case <-graceTimer.C:
+	}
+
+	terminate(cmd, processGroup, syscall.SIGKILL)
+	err := <-done
+	var exitErr *exec.ExitError
+	errors.As(err, &exitErr)
+	return &TimeoutError{Timeout: d, err: exitErr}
+}
@@ -0,0 +1,35 @@
+package runcmd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/runcmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeoutKillsProcessGroup(t *testing.T) {
+	ctx := runcmd.Context(context.Background()).
+		WithTimeout(100 * time.Millisecond).
+		WithKillGracePeriod(100 * time.Millisecond)
+
+	start := time.Now()
+	err := runcmd.Run(ctx, "sleep", "5")
+	elapsed := time.Since(start)
+
+	if !assert.Error(t, err, `Run should fail once the timeout elapses`) {
+		return
+	}
+
+	var timeoutErr *runcmd.TimeoutError
+	assert.True(t, errors.As(err, &timeoutErr), `error should be a *runcmd.TimeoutError`)
+	assert.Less(t, elapsed, 2*time.Second, `Run should return well before the full sleep duration`)
+}
+
+func TestWithTimeoutDoesNotFireOnFastCommand(t *testing.T) {
+	ctx := runcmd.Context(context.Background()).WithTimeout(time.Second)
+	err := runcmd.Run(ctx, "true")
+	assert.NoError(t, err, `a command finishing before the timeout should succeed normally`)
+}
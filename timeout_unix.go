@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package runcmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in its own process group so terminate can
+// signal every descendant at once instead of just the direct child.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminate(cmd *exec.Cmd, processGroup bool, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	if processGroup {
+		// Negative pid targets the whole process group (valid because
+		// setProcessGroup made cmd.Process.Pid the group leader).
+		_ = syscall.Kill(-cmd.Process.Pid, sig)
+		return
+	}
+	_ = cmd.Process.Signal(sig)
+}
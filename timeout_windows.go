@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package runcmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const ctrlBreakEvent = 1
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procGenerateConsoleCtrlEvent = kernel32.NewProc("GenerateConsoleCtrlEvent")
+)
+
+// setProcessGroup starts cmd in a new process group so terminate can
+// target every descendant via GenerateConsoleCtrlEvent instead of just
+// the direct child.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+func terminate(cmd *exec.Cmd, processGroup bool, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	if processGroup && sig == syscall.SIGTERM {
+		procGenerateConsoleCtrlEvent.Call(ctrlBreakEvent, uintptr(cmd.Process.Pid))
+		return
+	}
+	_ = cmd.Process.Kill()
+}